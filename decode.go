@@ -7,7 +7,6 @@ package form
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -17,26 +16,65 @@ import (
 
 // NewDecoder returns a new form decoder.
 func NewDecoder(r io.Reader) *decoder {
-	return &decoder{r}
+	return &decoder{r: r}
 }
 
 // decoder decodes data from a form (application/x-www-form-urlencoded).
 type decoder struct {
-	r io.Reader
+	r       io.Reader
+	options options
 }
 
-// Decode reads in and decodes form-encoded data into dst.
+// SetDecodeHooks sets the chain of DecodeHookFunc that raw form values are
+// passed through, in order, before the built-in decodeBasic/decodeTime
+// logic runs. It returns d so calls can be chained off of NewDecoder.
+func (d *decoder) SetDecodeHooks(hooks ...DecodeHookFunc) *decoder {
+	d.options.hooks = hooks
+	return d
+}
+
+// Strict controls whether unknown form keys and "required"/"min"/"max"/
+// "pattern" struct tag constraints are enforced. When strict is false (the
+// default), unknown keys are silently ignored and constraints aren't
+// checked. It returns d so calls can be chained off of NewDecoder.
+func (d *decoder) Strict(strict bool) *decoder {
+	d.options.strict = strict
+	return d
+}
+
+// SetKeyMapper sets a global transform applied to every form key before it
+// is matched against a destination struct's fields, e.g. to accept
+// snake_case form keys against CamelCase Go field names. It returns d so
+// calls can be chained off of NewDecoder.
+func (d *decoder) SetKeyMapper(m func(string) string) *decoder {
+	d.options.keyMapper = m
+	return d
+}
+
+// SetLimits bounds how much of d.r will be read and how many keys/how long
+// a key may be, guarding against parameter-pollution DoS from large or
+// adversarial bodies. A value of 0 leaves the corresponding limit
+// unenforced. It returns d so calls can be chained off of NewDecoder.
+func (d *decoder) SetLimits(maxBodyBytes int64, maxKeys, maxKeyLength int) *decoder {
+	d.options.limits = limits{maxBodyBytes, maxKeys, maxKeyLength}
+	return d
+}
+
+// Decode reads in and decodes form-encoded data into dst. This is bounded
+// reading with DoS limits, not an incremental decoder: unlike
+// url.ParseQuery, it scans key=value pairs directly off of d.r via
+// scanPairs rather than calling ioutil.ReadAll first, so a body that trips
+// a limit set with SetLimits is rejected partway through instead of being
+// read to completion, but the parsed pairs are still accumulated into a
+// url.Values and node tree, as DecodeString/DecodeValues also do, before
+// the reflect-based decode below runs.
 func (d decoder) Decode(dst interface{}) error {
-	bs, err := ioutil.ReadAll(d.r)
-	if err != nil {
-		return err
-	}
-	vs, err := url.ParseQuery(string(bs))
+	vs, err := scanPairs(d.r, d.options.limits)
 	if err != nil {
 		return err
 	}
 	v := reflect.ValueOf(dst)
-	return decodeNode(v, parseValues(vs, canIndex(v)))
+	return decodeNode(v, parseValues(vs, canIndex(v)), &d.options)
 }
 
 // DecodeString decodes src into dst.
@@ -46,121 +84,190 @@ func DecodeString(dst interface{}, src string) error {
 		return err
 	}
 	v := reflect.ValueOf(dst)
-	return decodeNode(v, parseValues(vs, canIndex(v)))
+	return decodeNode(v, parseValues(vs, canIndex(v)), &options{})
 }
 
 // DecodeValues decodes vs into dst.
 func DecodeValues(dst interface{}, vs url.Values) error {
 	v := reflect.ValueOf(dst)
-	return decodeNode(v, parseValues(vs, canIndex(v)))
+	return decodeNode(v, parseValues(vs, canIndex(v)), &options{})
 }
 
-func decodeNode(v reflect.Value, n node) (err error) {
-	defer func() {
-		if e := recover(); e != nil {
-			err = fmt.Errorf("%v", e)
-		}
-	}()
+// DecodeStringStrict is like DecodeString but enforces the same
+// constraints as decoder.Strict(true): unknown keys become errors and
+// "required"/"min"/"max"/"pattern" struct tags are validated.
+func DecodeStringStrict(dst interface{}, src string) error {
+	vs, err := url.ParseQuery(src)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(dst)
+	return decodeNode(v, parseValues(vs, canIndex(v)), &options{strict: true})
+}
 
+// decodeNode is the entry point for decoding a parsed node tree into v. It
+// relies on every decode* function below returning the errors it (and its
+// children) encountered rather than panicking, so a single bad leaf never
+// prevents the rest of dst from being populated.
+func decodeNode(v reflect.Value, n node, o *options) error {
 	if v.Kind() == reflect.Slice {
 		return fmt.Errorf("could not decode directly into slice; use pointer to slice")
 	}
-	decodeValue(v, n)
-	return nil
+	return asError(decodeValue(o, nil, v, n))
 }
 
-func decodeValue(v reflect.Value, x interface{}) {
+func decodeValue(o *options, path []string, v reflect.Value, x interface{}) []error {
+	if !v.IsValid() { // E.g. the Elem of a nil pointer or interface.
+		return []error{&DecodeError{Path: path, Kind: reflect.Invalid, Cause: fmt.Errorf("cannot decode into a nil pointer or interface")}}
+	}
 	t := v.Type()
 	k := v.Kind()
 
 	switch k {
 	case reflect.Ptr, reflect.Interface:
-		decodeValue(v.Elem(), x)
-		return
+		return decodeValue(o, path, v.Elem(), x)
 	}
 
-	if s, ok := x.(string); ok && s == "" { // Treat the empty string as the zero value.
+	if s, ok := x.(string); ok && s == "" { // Treat the empty string as the zero value, ahead of the hook chain.
 		v.Set(reflect.Zero(t))
-		return
+		return nil
+	}
+
+	hookRan := false
+	if s, ok := x.(string); ok && len(o.hooks) > 0 {
+		var err error
+		if x, err = runDecodeHooks(o.hooks, reflect.TypeOf(s), t, s); err != nil {
+			field := ""
+			if len(path) > 0 {
+				field = path[len(path)-1]
+			}
+			return []error{TypeConversionError(path, field, k, s, err)}
+		}
+		hookRan = true
+	}
+
+	if hookRan {
+		// A hook may have already turned x into a value of (or convertible
+		// to) v's own type -- e.g. StringToDurationHook returning a
+		// time.Duration, StringToIPHook returning a net.IP, or
+		// TextUnmarshalerHook returning a populated user type -- in which
+		// case there's nothing left for decodeBasic/decodeCustom's
+		// string-oriented logic to do; assign it directly. []string is
+		// excluded here because it's handled specially by decodeSlice and
+		// decodeCustom (e.g. StringToSliceHook feeding a repeated field).
+		_, isString := x.(string)
+		_, isStringSlice := x.([]string)
+		if !isString && !isStringSlice {
+			if xt := reflect.TypeOf(x); xt != nil && xt.ConvertibleTo(t) {
+				v.Set(reflect.ValueOf(x).Convert(t))
+				return nil
+			}
+		}
+	}
+
+	// Unless a hook above already produced the value, time.Time (and types
+	// convertible to it) decode via the fixed allowedTimeFormats list, ahead
+	// of decodeCustom: time.Time satisfies
+	// encoding.TextUnmarshaler itself (RFC3339 only), so letting
+	// decodeCustom run first would silently drop every other supported
+	// format.
+	if t.ConvertibleTo(timeType) {
+		return decodeTime(path, v, x)
+	}
+
+	if errs, handled := decodeCustom(path, v, x); handled {
+		return errs
 	}
 
 	switch k {
 	case reflect.Struct:
-		if t.ConvertibleTo(timeType) {
-			decodeTime(v, x)
-		} else {
-			decodeStruct(v, x)
-		}
+		return decodeStruct(o, path, v, x)
 	case reflect.Slice:
-		decodeSlice(v, x)
+		return decodeSlice(o, path, v, x)
 	case reflect.Array:
-		decodeArray(v, x)
+		return decodeArray(o, path, v, x)
 	case reflect.Map:
-		decodeMap(v, x)
+		return decodeMap(o, path, v, x)
 	case reflect.Invalid, reflect.Uintptr, reflect.UnsafePointer,
 		reflect.Complex64, reflect.Complex128, reflect.Chan, reflect.Func:
-		panic(t.String() + " has unsupported kind " + t.Kind().String())
+		return []error{&DecodeError{
+			Path:  path,
+			Kind:  k,
+			Cause: fmt.Errorf("%s has unsupported kind %s", t, t.Kind()),
+		}}
 	default:
-		decodeBasic(v, x)
+		return decodeBasic(path, v, x)
 	}
 }
 
 func fieldInfo(f reflect.StructField) (k string, oe bool) {
-	if f.PkgPath != "" { // Skip private fields.
-		return "-", oe
-	}
+	t := fieldTagOf(f)
+	return t.name, t.omitempty
+}
 
-	k = f.Name
-	tag := f.Tag.Get("form")
-	if tag == "" {
-		return k, oe
-	}
+func findField(v reflect.Value, n string) (reflect.Value, bool) {
+	f, _, ok := findFieldTag(v, n)
+	return f, ok
+}
 
-	ps := strings.SplitN(tag, ",", 2)
-	if ps[0] != "" {
-		k = ps[0]
+// findFieldTag is like findField but also returns the parsed form tag. It
+// looks the key up in v's cached structInfo: first an exact match against
+// the field's name/aliases, then a case-insensitive fallback, so lookups
+// stay O(1) instead of scanning every field on every call.
+func findFieldTag(v reflect.Value, n string) (reflect.Value, fieldTag, bool) {
+	si := structInfoFor(v.Type())
+	if i, ok := si.byName[n]; ok {
+		return v.Field(i), si.tags[i], true
 	}
-	if len(ps) == 2 {
-		oe = ps[1] == "omitempty"
+	if i, ok := si.byLower[strings.ToLower(n)]; ok {
+		return v.Field(i), si.tags[i], true
 	}
-	return k, oe
+	return reflect.Value{}, fieldTag{}, false
 }
 
-func findField(v reflect.Value, n string) (reflect.Value, bool) {
+func decodeStruct(o *options, path []string, v reflect.Value, x interface{}) []error {
 	t := v.Type()
-	for i, l := 0, v.NumField(); i < l; i++ {
-		f := t.Field(i)
-		k, _ := fieldInfo(f)
-		if k == "-" {
+	var errs []error
+	seen := map[string]bool{}
+	for k, c := range getNode(x) {
+		p := withKey(path, k)
+		n := k
+		if o.keyMapper != nil {
+			n = o.keyMapper(k)
+		}
+		f, ft, ok := findFieldTag(v, n)
+		if !ok {
+			if o.strict {
+				errs = append(errs, UnknownFieldError(p, k, t.String()))
+			}
 			continue
-		} else if n == k {
-			return v.Field(i), true
 		}
-	}
-	return reflect.Value{}, false
-}
-
-func decodeStruct(v reflect.Value, x interface{}) {
-	t := v.Type()
-	for k, c := range getNode(x) {
-		if f, ok := findField(v, k); !ok {
-			panic(k + " doesn't exist in " + t.String())
-		} else if !f.CanSet() {
-			panic(k + " cannot be set in " + t.String())
-		} else {
-			decodeValue(f, c)
+		seen[ft.name] = true
+		if !f.CanSet() {
+			errs = append(errs, &DecodeError{Path: p, Field: k, Cause: fmt.Errorf("%s cannot be set in %s", k, t)})
+			continue
+		}
+		errs = append(errs, decodeValue(o, p, f, c)...)
+		if o.strict {
+			errs = append(errs, validateField(p, ft, f)...)
 		}
 	}
+	if o.strict {
+		errs = append(errs, checkRequiredFields(path, t, seen)...)
+	}
+	return errs
 }
 
-func decodeMap(v reflect.Value, x interface{}) {
+func decodeMap(o *options, path []string, v reflect.Value, x interface{}) []error {
 	t := v.Type()
 	if v.IsNil() {
 		v.Set(reflect.MakeMap(t))
 	}
+	var errs []error
 	for k, c := range getNode(x) {
+		p := withKey(path, k)
 		i := reflect.New(t.Key()).Elem()
-		decodeBasic(i, k) // TODO: decodeValue.
+		errs = append(errs, decodeBasic(p, i, k)...) // TODO: decodeValue.
 
 		w := v.MapIndex(i)
 		if w.IsValid() { // We have an actual element value to decode into.
@@ -180,59 +287,89 @@ func decodeMap(v reflect.Value, x interface{}) {
 			case string:
 				w = reflect.New(stringType).Elem()
 			default:
-				panic("value is neither node nor string")
+				errs = append(errs, &DecodeError{Path: p, Cause: fmt.Errorf("value is neither node nor string")})
+				continue
 			}
 		}
 
-		decodeValue(w, c)
+		errs = append(errs, decodeValue(o, p, w, c)...)
 		v.SetMapIndex(i, w)
 	}
+	return errs
 }
 
-func decodeArray(v reflect.Value, x interface{}) {
+func decodeArray(o *options, path []string, v reflect.Value, x interface{}) []error {
 	t := v.Type()
+	var errs []error
 	for k, c := range getNode(x) {
+		p := withKey(path, k)
 		i, err := strconv.Atoi(k)
 		if err != nil {
-			panic(k + " is not a valid index for type " + t.String())
+			errs = append(errs, &DecodeError{Path: p, Cause: fmt.Errorf("%s is not a valid index for type %s", k, t)})
+			continue
 		}
 		if l := v.Len(); i >= l {
-			panic("index is above array size")
+			errs = append(errs, IndexOutOfRangeError(p, i, l, t.String()))
+			continue
 		}
-		decodeValue(v.Index(i), c)
+		errs = append(errs, decodeValue(o, p, v.Index(i), c)...)
 	}
+	return errs
 }
 
-func decodeSlice(v reflect.Value, x interface{}) {
+func decodeSlice(o *options, path []string, v reflect.Value, x interface{}) []error {
 	t := v.Type()
 	if t.Elem().Kind() == reflect.Uint8 {
 		// Allow, but don't require, byte slices to be encoded as a single string.
 		if s, ok := x.(string); ok {
 			v.SetBytes([]byte(s))
-			return
+			return nil
+		}
+	}
+
+	if ss, ok := x.([]string); ok {
+		// A decode hook (e.g. StringToSliceHook) may turn a single form
+		// value into a list of scalars rather than an indexed node.
+		var errs []error
+		if l := v.Len(); len(ss) > l {
+			delta := len(ss) - l
+			v.Set(reflect.AppendSlice(v, reflect.MakeSlice(t, delta, delta)))
+		}
+		for i, s := range ss {
+			errs = append(errs, decodeValue(o, withKey(path, strconv.Itoa(i)), v.Index(i), s)...)
 		}
+		return errs
 	}
 
+	var errs []error
 	for k, c := range getNode(x) {
+		p := withKey(path, k)
 		i, err := strconv.Atoi(k)
 		if err != nil {
-			panic(k + " is not a valid index for type " + t.String())
+			errs = append(errs, &DecodeError{Path: p, Cause: fmt.Errorf("%s is not a valid index for type %s", k, t)})
+			continue
 		}
 		// "Extend" the slice if it's too short.
 		if l := v.Len(); i >= l {
 			delta := i - l + 1
 			v.Set(reflect.AppendSlice(v, reflect.MakeSlice(t, delta, delta)))
 		}
-		decodeValue(v.Index(i), c)
+		errs = append(errs, decodeValue(o, p, v.Index(i), c)...)
 	}
+	return errs
 }
 
-func decodeBasic(v reflect.Value, x interface{}) {
+func decodeBasic(path []string, v reflect.Value, x interface{}) []error {
 	t := v.Type()
 	s := getString(x)
 	if s == "" {
 		v.Set(reflect.Zero(t)) // Treat the empty string as the zero value.
-		return
+		return nil
+	}
+
+	field := ""
+	if len(path) > 0 {
+		field = path[len(path)-1]
 	}
 
 	switch k := t.Kind(); k {
@@ -240,7 +377,7 @@ func decodeBasic(v reflect.Value, x interface{}) {
 		if b, e := strconv.ParseBool(s); e == nil {
 			v.SetBool(b)
 		} else {
-			panic("could not parse bool from " + s)
+			return []error{TypeConversionError(path, field, k, s, fmt.Errorf("could not parse bool from %s", s))}
 		}
 	case reflect.Int,
 		reflect.Int8,
@@ -250,7 +387,7 @@ func decodeBasic(v reflect.Value, x interface{}) {
 		if i, e := strconv.ParseInt(s, 10, 64); e == nil {
 			v.SetInt(i)
 		} else {
-			panic("could not parse int from " + s)
+			return []error{TypeConversionError(path, field, k, s, fmt.Errorf("could not parse int from %s", s))}
 		}
 	case reflect.Uint,
 		reflect.Uint8,
@@ -260,36 +397,41 @@ func decodeBasic(v reflect.Value, x interface{}) {
 		if u, e := strconv.ParseUint(s, 10, 64); e == nil {
 			v.SetUint(u)
 		} else {
-			panic("could not parse uint from " + s)
+			return []error{TypeConversionError(path, field, k, s, fmt.Errorf("could not parse uint from %s", s))}
 		}
 	case reflect.Float32,
 		reflect.Float64:
 		if f, e := strconv.ParseFloat(s, 64); e == nil {
 			v.SetFloat(f)
 		} else {
-			panic("could not parse float from " + s)
+			return []error{TypeConversionError(path, field, k, s, fmt.Errorf("could not parse float from %s", s))}
 		}
 	case reflect.String:
 		v.SetString(s)
 	default:
-		panic(t.String() + " has unsupported kind " + t.Kind().String())
+		return []error{TypeConversionError(path, field, k, s, fmt.Errorf("%s has unsupported kind %s", t, t.Kind()))}
 	}
+	return nil
 }
 
-func decodeTime(v reflect.Value, x interface{}) {
+func decodeTime(path []string, v reflect.Value, x interface{}) []error {
 	t := v.Type()
 	s := getString(x)
 	if s == "" {
 		v.Set(reflect.Zero(v.Type())) // Treat the empty string as the zero value.
-		return
+		return nil
 	}
 	for _, f := range allowedTimeFormats {
 		if p, err := time.Parse(f, s); err == nil {
 			v.Set(reflect.ValueOf(p).Convert(v.Type()))
-			return
+			return nil
 		}
 	}
-	panic("cannot decode string `" + s + "` as " + t.String())
+	field := ""
+	if len(path) > 0 {
+		field = path[len(path)-1]
+	}
+	return []error{TypeConversionError(path, field, reflect.Struct, s, fmt.Errorf("cannot decode string `%s` as %s", s, t))}
 }
 
 // TODO: Find a more efficient way to do this.
@@ -321,4 +463,4 @@ var allowedTimeFormats = []string{
 	"15:04",
 	"15Z",
 	"15",
-}
\ No newline at end of file
+}
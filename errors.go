@@ -0,0 +1,114 @@
+// Copyright 2013 Alvaro J. Genial. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package form
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodeError describes the failure to decode a single value somewhere in
+// the destination tree. Path identifies the sequence of form keys (and/or
+// array/slice indices) leading to the offending value, Field and Kind
+// describe what was being decoded into, and Value holds the raw string
+// that could not be used. Cause is the underlying reason and is always
+// non-nil.
+type DecodeError struct {
+	Path  []string
+	Field string
+	Kind  reflect.Kind
+	Value string
+	Cause error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	msg := e.Cause.Error()
+	if len(e.Path) == 0 {
+		return msg
+	}
+	return strings.Join(e.Path, ".") + ": " + msg
+}
+
+// Unwrap allows DecodeError to be used with errors.Is and errors.As.
+func (e *DecodeError) Unwrap() error {
+	return e.Cause
+}
+
+// ErrorList collects every DecodeError produced while decoding a single
+// form, so that a single malformed leaf doesn't prevent the rest of the
+// destination from being populated. It implements error so it can still be
+// returned (and compared with nil) like any other decoding failure.
+type ErrorList []error
+
+// Error implements the error interface, joining every contained error.
+func (es ErrorList) Error() string {
+	switch len(es) {
+	case 0:
+		return "no errors"
+	case 1:
+		return es[0].Error()
+	}
+	ss := make([]string, len(es))
+	for i, e := range es {
+		ss[i] = e.Error()
+	}
+	return strings.Join(ss, "; ")
+}
+
+// asError collapses a slice of errors (as accumulated during traversal)
+// into nil, a single error, or an ErrorList, whichever is most precise.
+func asError(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return ErrorList(errs)
+	}
+}
+
+// withKey returns a copy of path with key appended, so that callers can
+// safely reuse path across sibling iterations without aliasing.
+func withKey(path []string, key string) []string {
+	p := make([]string, len(path)+1)
+	copy(p, path)
+	p[len(path)] = key
+	return p
+}
+
+// UnknownFieldError reports that field has no corresponding field in typ.
+func UnknownFieldError(path []string, field, typ string) *DecodeError {
+	return &DecodeError{
+		Path:  path,
+		Field: field,
+		Cause: fmt.Errorf("%s doesn't exist in %s", field, typ),
+	}
+}
+
+// IndexOutOfRangeError reports that index is out of range for typ, which
+// has the given length.
+func IndexOutOfRangeError(path []string, index, length int, typ string) *DecodeError {
+	return &DecodeError{
+		Path:  path,
+		Value: strconv.Itoa(index),
+		Cause: fmt.Errorf("index %d is out of range for %s of length %d", index, typ, length),
+	}
+}
+
+// TypeConversionError reports that value could not be converted to kind
+// while decoding field, because of cause.
+func TypeConversionError(path []string, field string, kind reflect.Kind, value string, cause error) *DecodeError {
+	return &DecodeError{
+		Path:  path,
+		Field: field,
+		Kind:  kind,
+		Value: value,
+		Cause: cause,
+	}
+}
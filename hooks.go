@@ -0,0 +1,122 @@
+// Copyright 2013 Alvaro J. Genial. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package form
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// options holds the per-Decoder configuration consulted during decoding. It
+// is always passed by pointer so that package-level helpers such as
+// DecodeString, which have no Decoder to hang options off of, can share the
+// same code paths as decoder.Decode by passing a zero-value options.
+type options struct {
+	hooks     []DecodeHookFunc
+	strict    bool
+	limits    limits
+	keyMapper func(string) string
+}
+
+// DecodeHookFunc is applied, in the order given to SetDecodeHooks, to every
+// raw string form value before it reaches the built-in decodeBasic/
+// decodeTime logic. from is the type of data (always a string on the first
+// hook in the chain, but subsequent hooks see whatever the previous hook
+// returned); to is the type of the destination field. A hook that has
+// nothing to say about the (from, to) pair should return data unchanged.
+type DecodeHookFunc func(from, to reflect.Type, data interface{}) (interface{}, error)
+
+// runDecodeHooks threads data through hooks in order, feeding the output of
+// each hook into the next.
+func runDecodeHooks(hooks []DecodeHookFunc, from, to reflect.Type, data interface{}) (interface{}, error) {
+	var err error
+	for _, h := range hooks {
+		data, err = h(from, to, data)
+		if err != nil {
+			return nil, err
+		}
+		from = reflect.TypeOf(data)
+	}
+	return data, nil
+}
+
+// StringToTimeHookFunc returns a DecodeHookFunc that parses string values
+// destined for a time.Time (or a type convertible to it) using layout,
+// supplementing the fixed allowedTimeFormats tried by decodeTime.
+func StringToTimeHookFunc(layout string) DecodeHookFunc {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || !to.ConvertibleTo(timeType) {
+			return data, nil
+		}
+		if t, err := time.Parse(layout, data.(string)); err == nil {
+			return t, nil
+		}
+		return data, nil // Leave it to decodeTime's allowedTimeFormats.
+	}
+}
+
+// StringToDurationHook converts a string value into a time.Duration using
+// time.ParseDuration.
+func StringToDurationHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != durationType {
+		return data, nil
+	}
+	return time.ParseDuration(data.(string))
+}
+
+// StringToIPHook converts a string value into a net.IP.
+func StringToIPHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != ipType {
+		return data, nil
+	}
+	s := data.(string)
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("could not parse IP from %s", s)
+	}
+	return ip, nil
+}
+
+// StringToSliceHook returns a DecodeHookFunc that splits a single string
+// value on sep into a []string whenever the destination is a slice of
+// strings, letting a form value like "a,b,c" populate a []string field.
+func StringToSliceHook(sep string) DecodeHookFunc {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to.Kind() != reflect.Slice || to.Elem().Kind() != reflect.String {
+			return data, nil
+		}
+		s := data.(string)
+		if s == "" {
+			return []string{}, nil
+		}
+		return strings.Split(s, sep), nil
+	}
+}
+
+// TextUnmarshalerHook decodes a string value via to's encoding.TextUnmarshaler
+// implementation, if it has one.
+func TextUnmarshalerHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String {
+		return data, nil
+	}
+	ptr := reflect.New(to)
+	u, ok := ptr.Interface().(encoding.TextUnmarshaler)
+	if !ok {
+		return data, nil
+	}
+	if err := u.UnmarshalText([]byte(data.(string))); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	ipType       = reflect.TypeOf(net.IP{})
+)
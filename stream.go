@@ -0,0 +1,106 @@
+// Copyright 2013 Alvaro J. Genial. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package form
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strings"
+)
+
+// limits bounds how much of a form body decoder.Decode will consume. A
+// zero value (the default) leaves all three limits unenforced.
+type limits struct {
+	maxBodyBytes int64
+	maxKeys      int
+	maxKeyLength int
+}
+
+// LimitExceededError is returned by decoder.Decode when the body being
+// scanned violates one of the limits set with decoder.SetLimits.
+type LimitExceededError struct {
+	// Limit is the name of the limit that was exceeded: "MaxBodyBytes",
+	// "MaxKeys" or "MaxKeyLength".
+	Limit string
+	Value int64
+	Max   int64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s exceeded: %d > %d", e.Limit, e.Value, e.Max)
+}
+
+// scanPairs provides bounded reading with DoS limits, not incremental
+// decoding: it reads "key=value&key=value&..." pairs off of r one at a
+// time, enforcing lim as it goes, and URL-unescapes them into a
+// url.Values, but that url.Values is still built and handed to the caller
+// in full rather than being applied to a destination as each pair is read.
+// It never buffers the whole body in one contiguous string the way
+// ioutil.ReadAll followed by url.ParseQuery does. When lim.maxBodyBytes is
+// set, r is first wrapped in an io.LimitReader capped just past it, so a
+// single oversized pair (e.g. one key with no '&' in sight) can't be
+// buffered in full by ReadString before the MaxBodyBytes check below ever
+// runs.
+func scanPairs(r io.Reader, lim limits) (url.Values, error) {
+	if lim.maxBodyBytes > 0 {
+		n := lim.maxBodyBytes
+		if n < math.MaxInt64 { // Avoid overflowing into a negative cap for a limit set near math.MaxInt64.
+			n++
+		}
+		r = io.LimitReader(r, n)
+	}
+	br := bufio.NewReader(r)
+	vs := url.Values{}
+	var total int64
+	var keys int
+
+	for {
+		chunk, err := br.ReadString('&')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		done := err == io.EOF
+
+		total += int64(len(chunk))
+		if lim.maxBodyBytes > 0 && total > lim.maxBodyBytes {
+			return nil, &LimitExceededError{Limit: "MaxBodyBytes", Value: total, Max: lim.maxBodyBytes}
+		}
+
+		pair := strings.TrimSuffix(chunk, "&")
+		if pair != "" {
+			keys++
+			if lim.maxKeys > 0 && keys > lim.maxKeys {
+				return nil, &LimitExceededError{Limit: "MaxKeys", Value: int64(keys), Max: int64(lim.maxKeys)}
+			}
+
+			key, value := pair, ""
+			if i := strings.IndexByte(pair, '='); i >= 0 {
+				key, value = pair[:i], pair[i+1:]
+			}
+			if lim.maxKeyLength > 0 && len(key) > lim.maxKeyLength {
+				return nil, &LimitExceededError{Limit: "MaxKeyLength", Value: int64(len(key)), Max: int64(lim.maxKeyLength)}
+			}
+
+			uk, err := url.QueryUnescape(key)
+			if err != nil {
+				return nil, err
+			}
+			uv, err := url.QueryUnescape(value)
+			if err != nil {
+				return nil, err
+			}
+			vs[uk] = append(vs[uk], uv)
+		}
+
+		if done {
+			break
+		}
+	}
+
+	return vs, nil
+}
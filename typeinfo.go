@@ -0,0 +1,68 @@
+// Copyright 2013 Alvaro J. Genial. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package form
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// structInfo precomputes, for a struct type, everything findFieldTag needs
+// to resolve a form key to a field in O(1): an exact-match index keyed by
+// every field's name and aliases, a case-insensitive fallback index, and
+// the parsed tag for each field (in field order, for iterating required
+// fields).
+type structInfo struct {
+	byName  map[string]int
+	byLower map[string]int
+	tags    []fieldTag
+}
+
+var structInfoCache sync.Map // reflect.Type -> *structInfo
+
+// structInfoFor returns the cached structInfo for t, building and caching
+// it on first use.
+func structInfoFor(t reflect.Type) *structInfo {
+	if si, ok := structInfoCache.Load(t); ok {
+		return si.(*structInfo)
+	}
+	si, _ := structInfoCache.LoadOrStore(t, buildStructInfo(t))
+	return si.(*structInfo)
+}
+
+func buildStructInfo(t reflect.Type) *structInfo {
+	n := t.NumField()
+	si := &structInfo{
+		byName:  make(map[string]int, n),
+		byLower: make(map[string]int, n),
+		tags:    make([]fieldTag, n),
+	}
+
+	addKey := func(key string, i int) {
+		si.byName[key] = i
+		lower := strings.ToLower(key)
+		if _, exists := si.byLower[lower]; !exists {
+			si.byLower[lower] = i
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		ft := fieldTagOf(t.Field(i))
+		if ft.pattern != "" {
+			ft.patternRe, ft.patternErr = regexp.Compile(ft.pattern)
+		}
+		si.tags[i] = ft
+		if ft.name == "-" {
+			continue
+		}
+		addKey(ft.name, i)
+		for _, a := range ft.aliases {
+			addKey(a, i)
+		}
+	}
+	return si
+}
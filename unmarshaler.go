@@ -0,0 +1,70 @@
+// Copyright 2013 Alvaro J. Genial. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package form
+
+import (
+	"encoding"
+	"reflect"
+)
+
+// Unmarshaler is implemented by types that know how to decode themselves
+// from the raw string value(s) posted for a single form key, for cases
+// that encoding.TextUnmarshaler (a single string) can't express, such as a
+// field that accepts repeated values (e.g. "tag=a&tag=b").
+type Unmarshaler interface {
+	UnmarshalForm([]string) error
+}
+
+// decodeCustom dispatches a scalar leaf (a string, or a []string produced
+// by a DecodeHookFunc such as StringToSliceHook) to Unmarshaler,
+// encoding.TextUnmarshaler or encoding.BinaryUnmarshaler, in that order of
+// preference, if v or v.Addr() implements one of them. handled reports
+// whether one of the interfaces took responsibility for decoding x, so the
+// caller can skip its own reflect-based kind switch.
+func decodeCustom(path []string, v reflect.Value, x interface{}) (errs []error, handled bool) {
+	ss, isSlice := x.([]string)
+	s, isString := x.(string)
+	if !isSlice && !isString {
+		return nil, false
+	}
+	if !isSlice {
+		ss = []string{s}
+	}
+
+	field := ""
+	if len(path) > 0 {
+		field = path[len(path)-1]
+	}
+
+	// v.Addr()'s method set includes v's, so it is tried first to keep the
+	// order of preference across value and pointer receivers.
+	var candidates []reflect.Value
+	if v.CanAddr() {
+		candidates = append(candidates, v.Addr())
+	}
+	candidates = append(candidates, v)
+
+	for _, c := range candidates {
+		if u, ok := c.Interface().(Unmarshaler); ok {
+			if err := u.UnmarshalForm(ss); err != nil {
+				return []error{TypeConversionError(path, field, v.Kind(), s, err)}, true
+			}
+			return nil, true
+		}
+		if u, ok := c.Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(s)); err != nil {
+				return []error{TypeConversionError(path, field, v.Kind(), s, err)}, true
+			}
+			return nil, true
+		}
+		if u, ok := c.Interface().(encoding.BinaryUnmarshaler); ok {
+			if err := u.UnmarshalBinary([]byte(s)); err != nil {
+				return []error{TypeConversionError(path, field, v.Kind(), s, err)}, true
+			}
+			return nil, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,162 @@
+// Copyright 2013 Alvaro J. Genial. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package form
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fieldTag is the parsed form of a struct field's `form` tag.
+type fieldTag struct {
+	name      string
+	aliases   []string
+	omitempty bool
+	required  bool
+	min, max  string
+	pattern   string
+	// patternRe and patternErr are filled in once by buildStructInfo, so
+	// that pattern isn't recompiled on every decode of every instance of a
+	// type.
+	patternRe  *regexp.Regexp
+	patternErr error
+}
+
+// fieldTagOf parses f's `form` tag. Private fields, and fields explicitly
+// excluded with `form:"-"`, get name == "-".
+func fieldTagOf(f reflect.StructField) fieldTag {
+	if f.PkgPath != "" { // Skip private fields.
+		return fieldTag{name: "-"}
+	}
+
+	ft := fieldTag{name: f.Name}
+	tag := f.Tag.Get("form")
+	if tag == "" {
+		return ft
+	}
+
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		ft.name = parts[0]
+	}
+	for _, p := range parts[1:] {
+		switch {
+		case p == "omitempty":
+			ft.omitempty = true
+		case p == "required":
+			ft.required = true
+		case strings.HasPrefix(p, "min="):
+			ft.min = strings.TrimPrefix(p, "min=")
+		case strings.HasPrefix(p, "max="):
+			ft.max = strings.TrimPrefix(p, "max=")
+		case strings.HasPrefix(p, "pattern="):
+			ft.pattern = strings.TrimPrefix(p, "pattern=")
+		case strings.HasPrefix(p, "alias="):
+			ft.aliases = append(ft.aliases, strings.TrimPrefix(p, "alias="))
+		}
+	}
+	return ft
+}
+
+// validateField checks f, which has just been decoded, against the
+// min/max/pattern constraints in ft, in Strict mode.
+func validateField(path []string, ft fieldTag, f reflect.Value) []error {
+	var errs []error
+
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := f.Int()
+		if ft.min != "" {
+			if min, err := strconv.ParseInt(ft.min, 10, 64); err == nil && i < min {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: strconv.FormatInt(i, 10), Cause: fmt.Errorf("value %d is below minimum %d", i, min)})
+			}
+		}
+		if ft.max != "" {
+			if max, err := strconv.ParseInt(ft.max, 10, 64); err == nil && i > max {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: strconv.FormatInt(i, 10), Cause: fmt.Errorf("value %d is above maximum %d", i, max)})
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := f.Uint()
+		if ft.min != "" {
+			if min, err := strconv.ParseUint(ft.min, 10, 64); err == nil && u < min {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: strconv.FormatUint(u, 10), Cause: fmt.Errorf("value %d is below minimum %d", u, min)})
+			}
+		}
+		if ft.max != "" {
+			if max, err := strconv.ParseUint(ft.max, 10, 64); err == nil && u > max {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: strconv.FormatUint(u, 10), Cause: fmt.Errorf("value %d is above maximum %d", u, max)})
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		n := f.Float()
+		if ft.min != "" {
+			if min, err := strconv.ParseFloat(ft.min, 64); err == nil && n < min {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: strconv.FormatFloat(n, 'g', -1, 64), Cause: fmt.Errorf("value %g is below minimum %g", n, min)})
+			}
+		}
+		if ft.max != "" {
+			if max, err := strconv.ParseFloat(ft.max, 64); err == nil && n > max {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: strconv.FormatFloat(n, 'g', -1, 64), Cause: fmt.Errorf("value %g is above maximum %g", n, max)})
+			}
+		}
+	case reflect.String:
+		s := f.String()
+		if ft.min != "" {
+			if min, err := strconv.Atoi(ft.min); err == nil && len(s) < min {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: s, Cause: fmt.Errorf("length %d is below minimum %d", len(s), min)})
+			}
+		}
+		if ft.max != "" {
+			if max, err := strconv.Atoi(ft.max); err == nil && len(s) > max {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: s, Cause: fmt.Errorf("length %d is above maximum %d", len(s), max)})
+			}
+		}
+		if ft.pattern != "" {
+			if ft.patternErr != nil {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name,
+					Cause: fmt.Errorf("invalid pattern %q: %v", ft.pattern, ft.patternErr)})
+			} else if !ft.patternRe.MatchString(s) {
+				errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+					Value: s, Cause: fmt.Errorf("value %q does not match pattern %q", s, ft.pattern)})
+			}
+		}
+	default:
+		if ft.min != "" || ft.max != "" || ft.pattern != "" {
+			errs = append(errs, &DecodeError{Path: path, Field: ft.name, Kind: f.Kind(),
+				Cause: fmt.Errorf("min/max/pattern constraints are not supported on kind %s", f.Kind())})
+		}
+	}
+
+	return errs
+}
+
+// checkRequiredFields reports an error for every `form:"...,required"`
+// field of t that wasn't present (recorded in seen) in the decoded form.
+func checkRequiredFields(path []string, t reflect.Type, seen map[string]bool) []error {
+	var errs []error
+	for _, ft := range structInfoFor(t).tags {
+		if ft.name == "-" || !ft.required || seen[ft.name] {
+			continue
+		}
+		errs = append(errs, &DecodeError{
+			Path:  withKey(path, ft.name),
+			Field: ft.name,
+			Cause: fmt.Errorf("%s is required", ft.name),
+		})
+	}
+	return errs
+}